@@ -3,6 +3,7 @@ package migration
 import (
 	"context"
 
+	snapshotclientset "github.com/kubernetes-csi/external-snapshotter/client/v8/clientset/versioned"
 	"github.com/pkg/errors"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -13,6 +14,9 @@ import (
 
 type kubernetesClient struct {
 	c kubernetes.Interface
+	// snap is nil unless the cluster advertises the snapshot.storage.k8s.io API group; the
+	// VolumeSnapshot* methods then return an error (see requireSnapshotClient).
+	snap snapshotclientset.Interface
 }
 
 var (
@@ -83,30 +87,21 @@ func (k *kubernetesClient) GetPV(ctx context.Context, name string) (*corev1.Pers
 func (k *kubernetesClient) UpdatePV(ctx context.Context, pv *corev1.PersistentVolume,
 	updateFunc func(*corev1.PersistentVolume),
 ) (*corev1.PersistentVolume, error) {
-	pv, err := k.c.CoreV1().PersistentVolumes().Get(ctx, pv.Name, getOpts)
-	if err != nil {
-		return nil, errors.Wrapf(err, "could not get PVC to update")
-	}
-
-	updateFunc(pv)
-
-	for {
-		updatedPV, err := k.c.CoreV1().PersistentVolumes().Update(ctx, pv, updateOpts)
-		if err == nil {
-			return updatedPV, nil
-		}
-		if !apierrors.IsConflict(err) {
-			return nil, err
-		}
-		log.Debugf("got a conflict, retrying...")
-
-		pv, err = k.c.CoreV1().PersistentVolumes().Get(ctx, pv.Name, getOpts)
+	var updatedPV *corev1.PersistentVolume
+	err := retryOnConflict(ctx, retryBackoff, func() error {
+		latest, err := k.c.CoreV1().PersistentVolumes().Get(ctx, pv.Name, getOpts)
 		if err != nil {
-			return nil, err
+			return errors.Wrapf(err, "could not get PV to update")
 		}
+		updateFunc(latest)
 
-		updateFunc(pv)
+		updatedPV, err = k.c.CoreV1().PersistentVolumes().Update(ctx, latest, updateOpts)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	return updatedPV, nil
 }
 
 // CreatePVC creates a PVC and returns it after the server has processed it. It does not fail if the
@@ -153,30 +148,21 @@ func (k *kubernetesClient) UpdatePVC(ctx context.Context,
 	pvc *corev1.PersistentVolumeClaim,
 	updateFunc func(*corev1.PersistentVolumeClaim),
 ) (*corev1.PersistentVolumeClaim, error) {
-	pvc, err := k.c.CoreV1().PersistentVolumeClaims(pvc.Namespace).Get(ctx, pvc.Name, getOpts)
-	if err != nil {
-		return nil, errors.Wrapf(err, "could not get PVC to update")
-	}
-
-	updateFunc(pvc)
-
-	for {
-		updatedPVC, err := k.c.CoreV1().PersistentVolumeClaims(pvc.Namespace).Update(ctx, pvc, updateOpts)
-		if err == nil {
-			return updatedPVC, nil
-		}
-		if !apierrors.IsConflict(err) {
-			return nil, err
-		}
-		log.Debugf("got a conflict, retrying...")
-
-		pvc, err = k.c.CoreV1().PersistentVolumeClaims(pvc.Namespace).Get(ctx, pvc.Name, getOpts)
+	var updatedPVC *corev1.PersistentVolumeClaim
+	err := retryOnConflict(ctx, retryBackoff, func() error {
+		latest, err := k.c.CoreV1().PersistentVolumeClaims(pvc.Namespace).Get(ctx, pvc.Name, getOpts)
 		if err != nil {
-			return nil, err
+			return errors.Wrapf(err, "could not get PVC to update")
 		}
+		updateFunc(latest)
 
-		updateFunc(pvc)
+		updatedPVC, err = k.c.CoreV1().PersistentVolumeClaims(pvc.Namespace).Update(ctx, latest, updateOpts)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	return updatedPVC, nil
 }
 
 func (k *kubernetesClient) GetNamespace(ctx context.Context, name string) (*corev1.Namespace, error) {
@@ -198,28 +184,19 @@ func (k *kubernetesClient) ListNamespaces(ctx context.Context) ([]corev1.Namespa
 func (k *kubernetesClient) UpdateNamespace(ctx context.Context, ns *corev1.Namespace,
 	updateFunc func(*corev1.Namespace),
 ) (*corev1.Namespace, error) {
-	ns, err := k.c.CoreV1().Namespaces().Get(ctx, ns.Name, getOpts)
-	if err != nil {
-		return nil, errors.Wrapf(err, "could not get PVC to update")
-	}
-
-	updateFunc(ns)
-
-	for {
-		updatedNS, err := k.c.CoreV1().Namespaces().Update(ctx, ns, updateOpts)
-		if err == nil {
-			return updatedNS, nil
-		}
-		if !apierrors.IsConflict(err) {
-			return nil, err
-		}
-		log.Debugf("got a conflict, retrying...")
-
-		ns, err = k.c.CoreV1().Namespaces().Get(ctx, ns.Name, getOpts)
+	var updatedNS *corev1.Namespace
+	err := retryOnConflict(ctx, retryBackoff, func() error {
+		latest, err := k.c.CoreV1().Namespaces().Get(ctx, ns.Name, getOpts)
 		if err != nil {
-			return nil, err
+			return errors.Wrapf(err, "could not get namespace to update")
 		}
+		updateFunc(latest)
 
-		updateFunc(ns)
+		updatedNS, err = k.c.CoreV1().Namespaces().Update(ctx, latest, updateOpts)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	return updatedNS, nil
 }
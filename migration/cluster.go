@@ -0,0 +1,201 @@
+package migration
+
+import (
+	"context"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v8/apis/volumesnapshot/v1"
+	snapshotclientset "github.com/kubernetes-csi/external-snapshotter/client/v8/clientset/versioned"
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	applyconfigurationscorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+var _ KubernetesClient = (*kubernetesClient)(nil)
+
+// KubernetesClient is the set of operations relokator needs against a single cluster. It exists so a
+// migration run can hold a SourceClient and a TargetClient pointing at different clusters (or, for an
+// in-cluster migration, the same one) behind the same interface.
+type KubernetesClient interface {
+	CreateJob(ctx context.Context, job *batchv1.Job) (*batchv1.Job, error)
+	GetJob(ctx context.Context, name, namespace string) (*batchv1.Job, error)
+	DeleteJob(ctx context.Context, name, namespace string) error
+	ListPods(ctx context.Context, namespace string) ([]corev1.Pod, error)
+	DeletePod(ctx context.Context, name, namespace string) error
+	GetPV(ctx context.Context, name string) (*corev1.PersistentVolume, error)
+	UpdatePV(ctx context.Context, pv *corev1.PersistentVolume, updateFunc func(*corev1.PersistentVolume)) (*corev1.PersistentVolume, error)
+	CreatePVC(ctx context.Context, pvc *corev1.PersistentVolumeClaim) (*corev1.PersistentVolumeClaim, error)
+	GetPVC(ctx context.Context, name, namespace string) (*corev1.PersistentVolumeClaim, error)
+	ListPVCs(ctx context.Context, namespace string) ([]corev1.PersistentVolumeClaim, error)
+	DeletePVC(ctx context.Context, name, namespace string) error
+	UpdatePVC(ctx context.Context, pvc *corev1.PersistentVolumeClaim, updateFunc func(*corev1.PersistentVolumeClaim)) (*corev1.PersistentVolumeClaim, error)
+	GetNamespace(ctx context.Context, name string) (*corev1.Namespace, error)
+	ListNamespaces(ctx context.Context) ([]corev1.Namespace, error)
+	UpdateNamespace(ctx context.Context, ns *corev1.Namespace, updateFunc func(*corev1.Namespace)) (*corev1.Namespace, error)
+
+	WaitForJobComplete(ctx context.Context, name, ns string) error
+	WaitForPVCBound(ctx context.Context, name, ns string) error
+	WaitForPVCDeleted(ctx context.Context, name, ns string) error
+	WaitForPodDeleted(ctx context.Context, name, ns string) error
+
+	PatchPV(ctx context.Context, cfg *applyconfigurationscorev1.PersistentVolumeApplyConfiguration, fieldManager string) (*corev1.PersistentVolume, error)
+	PatchPVC(ctx context.Context, cfg *applyconfigurationscorev1.PersistentVolumeClaimApplyConfiguration, fieldManager string) (*corev1.PersistentVolumeClaim, error)
+	PatchNamespace(ctx context.Context, cfg *applyconfigurationscorev1.NamespaceApplyConfiguration, fieldManager string) (*corev1.Namespace, error)
+	SupportsServerSideApply() (bool, error)
+
+	CreateVolumeSnapshot(ctx context.Context, vs *snapshotv1.VolumeSnapshot) (*snapshotv1.VolumeSnapshot, error)
+	GetVolumeSnapshot(ctx context.Context, name, namespace string) (*snapshotv1.VolumeSnapshot, error)
+	DeleteVolumeSnapshot(ctx context.Context, name, namespace string) error
+	CreateVolumeSnapshotContent(ctx context.Context, vsc *snapshotv1.VolumeSnapshotContent) (*snapshotv1.VolumeSnapshotContent, error)
+	GetVolumeSnapshotContent(ctx context.Context, name string) (*snapshotv1.VolumeSnapshotContent, error)
+	DeleteVolumeSnapshotContent(ctx context.Context, name string) error
+	ImportVolumeSnapshotContent(ctx context.Context, contentName string, pvc *corev1.PersistentVolumeClaim) (*corev1.PersistentVolumeClaim, error)
+	SnapshotClassForStorageClass(ctx context.Context, storageClassName string) (string, bool, error)
+	SelectMigrationStrategy(ctx context.Context, pvc *corev1.PersistentVolumeClaim) (MigrationStrategy, string, error)
+	MigratePVCViaSnapshot(ctx context.Context, pvc *corev1.PersistentVolumeClaim, targetNamespace, volumeSnapshotClassName string) (*corev1.PersistentVolumeClaim, error)
+
+	// Ping verifies the cluster is reachable and advertises the APIs relokator needs. requireSnapshot
+	// should be true when the migration run may use the snapshot strategy against this cluster.
+	Ping(ctx context.Context, requireSnapshot bool) error
+}
+
+// ClusterConfig configures the client built for a single named cluster: which kubeconfig context to
+// use, and the client-side rate limit to apply against that cluster's apiserver. QPS/Burst default to
+// rest.Config's own client-go defaults (5/10) when left zero.
+type ClusterConfig struct {
+	Context string
+	QPS     float32
+	Burst   int
+}
+
+// ClientFactory builds and caches a KubernetesClient per named cluster from a single kubeconfig,
+// letting one migration run address a source and a target cluster - or more, for fan-out scenarios -
+// without each call site juggling its own rest.Config.
+type ClientFactory struct {
+	clients map[string]*kubernetesClient
+}
+
+// NewClientFactory loads kubeconfigPath and builds a client for every named cluster in clusters,
+// switching to that cluster's context and applying its QPS/Burst. The snapshot clientset is built
+// best-effort per cluster: a cluster without the snapshot.storage.k8s.io CRDs installed still gets a
+// usable client, just one whose VolumeSnapshot* methods will fail if called (see kubernetesClient.snap).
+func NewClientFactory(kubeconfigPath string, clusters map[string]ClusterConfig) (*ClientFactory, error) {
+	f := &ClientFactory{clients: make(map[string]*kubernetesClient, len(clusters))}
+
+	for name, cc := range clusters {
+		restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfigPath},
+			&clientcmd.ConfigOverrides{CurrentContext: cc.Context},
+		).ClientConfig()
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not load kubeconfig for cluster %q (context %q)", name, cc.Context)
+		}
+
+		if cc.QPS > 0 {
+			restConfig.QPS = cc.QPS
+		}
+		if cc.Burst > 0 {
+			restConfig.Burst = cc.Burst
+		}
+
+		client, err := newKubernetesClient(restConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not build client for cluster %q", name)
+		}
+		f.clients[name] = client
+	}
+
+	return f, nil
+}
+
+// SourceAndTarget returns the KubernetesClients for a migration's source and target clusters, or an
+// error naming whichever of the two was not configured. sourceName and targetName may be equal, for an
+// in-cluster migration between namespaces.
+func (f *ClientFactory) SourceAndTarget(sourceName, targetName string) (source, target KubernetesClient, err error) {
+	source = f.ClientFor(sourceName)
+	if source == nil {
+		return nil, nil, errors.Errorf("no client configured for source cluster %q", sourceName)
+	}
+	target = f.ClientFor(targetName)
+	if target == nil {
+		return nil, nil, errors.Errorf("no client configured for target cluster %q", targetName)
+	}
+	return source, target, nil
+}
+
+// ClientFor returns the KubernetesClient for the named cluster, or nil if no such cluster was
+// configured. There is no second, ok-style return value: callers that need a non-nil client compare
+// the result against nil themselves.
+func (f *ClientFactory) ClientFor(clusterName string) KubernetesClient {
+	client, ok := f.clients[clusterName]
+	if !ok {
+		return nil
+	}
+	return client
+}
+
+// newKubernetesClient builds a kubernetesClient from restConfig. snap is left nil if the apiserver
+// does not advertise snapshot.storage.k8s.io/v1, since typed clientset construction succeeds
+// regardless of whether the CRDs are installed and so can't be used by itself to detect that.
+func newKubernetesClient(restConfig *rest.Config) (*kubernetesClient, error) {
+	c, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not build core clientset")
+	}
+
+	hasSnapshotAPI, err := hasAPIGroupVersion(c, "snapshot.storage.k8s.io/v1")
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not check for snapshot.storage.k8s.io/v1")
+	}
+
+	var snap snapshotclientset.Interface
+	if hasSnapshotAPI {
+		snap, err = snapshotclientset.NewForConfig(restConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not build snapshot clientset")
+		}
+	}
+
+	return &kubernetesClient{c: c, snap: snap}, nil
+}
+
+// hasAPIGroupVersion reports whether the apiserver behind c advertises groupVersion.
+func hasAPIGroupVersion(c kubernetes.Interface, groupVersion string) (bool, error) {
+	_, err := c.Discovery().ServerResourcesForGroupVersion(groupVersion)
+	switch {
+	case err == nil:
+		return true, nil
+	case apierrors.IsNotFound(err):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// Ping verifies the cluster is reachable and, if requireSnapshot is set, that it advertises the
+// snapshot.storage.k8s.io/v1 API group - relokator needs both batch/v1 (for the job-based strategy)
+// and, when selected, the snapshot API before it starts moving a PVC, rather than failing midway
+// through a migration.
+func (k *kubernetesClient) Ping(ctx context.Context, requireSnapshot bool) error {
+	version, err := k.c.Discovery().ServerVersion()
+	if err != nil {
+		return errors.Wrapf(err, "could not reach apiserver")
+	}
+	log.Debugf("reached apiserver %s", version.String())
+
+	if _, err := k.c.Discovery().ServerResourcesForGroupVersion("batch/v1"); err != nil {
+		return errors.Wrapf(err, "apiserver does not advertise batch/v1")
+	}
+
+	if requireSnapshot {
+		if _, err := k.c.Discovery().ServerResourcesForGroupVersion("snapshot.storage.k8s.io/v1"); err != nil {
+			return errors.Wrapf(err, "apiserver does not advertise snapshot.storage.k8s.io/v1")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,7 @@
+package migration
+
+import "github.com/sirupsen/logrus"
+
+// log is the package-level logger used by the retry, wait and Ping helpers to report transient
+// errors and poll progress.
+var log = logrus.New()
@@ -0,0 +1,101 @@
+package migration
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	applyconfigurationscorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+)
+
+// DefaultFieldManager is the field manager relokator identifies itself as when using Server-Side
+// Apply. It is exposed so a --field-manager flag can override it.
+const DefaultFieldManager = "relokator"
+
+// ResolveFieldManager returns override if set, and DefaultFieldManager otherwise. It is the function a
+// --field-manager flag's value should be passed through before reaching PatchPV/PatchPVC/PatchNamespace.
+func ResolveFieldManager(override string) string {
+	if override != "" {
+		return override
+	}
+	return DefaultFieldManager
+}
+
+// applyOpts are the Server-Side Apply options shared by PatchPV/PatchPVC/PatchNamespace. Force takes
+// ownership of fields another manager already owns instead of rejecting the apply.
+var applyOpts = metav1.ApplyOptions{Force: true}
+
+// PatchPV applies cfg to the PersistentVolume it names using Server-Side Apply under fieldManager,
+// taking ownership of whatever fields cfg sets.
+func (k *kubernetesClient) PatchPV(ctx context.Context, cfg *applyconfigurationscorev1.PersistentVolumeApplyConfiguration, fieldManager string) (*corev1.PersistentVolume, error) {
+	if cfg.Name == nil {
+		return nil, errors.Errorf("apply configuration must set name")
+	}
+
+	opts := applyOpts
+	opts.FieldManager = fieldManager
+
+	pv, err := k.c.CoreV1().PersistentVolumes().Apply(ctx, cfg, opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not apply PersistentVolume %q", *cfg.Name)
+	}
+	return pv, nil
+}
+
+// PatchPVC applies cfg to the PersistentVolumeClaim it names using Server-Side Apply under
+// fieldManager, taking ownership of whatever fields cfg sets.
+func (k *kubernetesClient) PatchPVC(ctx context.Context, cfg *applyconfigurationscorev1.PersistentVolumeClaimApplyConfiguration, fieldManager string) (*corev1.PersistentVolumeClaim, error) {
+	if cfg.Name == nil || cfg.Namespace == nil {
+		return nil, errors.Errorf("apply configuration must set name/namespace")
+	}
+
+	opts := applyOpts
+	opts.FieldManager = fieldManager
+
+	pvc, err := k.c.CoreV1().PersistentVolumeClaims(*cfg.Namespace).Apply(ctx, cfg, opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not apply PersistentVolumeClaim %q", *cfg.Name)
+	}
+	return pvc, nil
+}
+
+// PatchNamespace applies cfg to the Namespace it names using Server-Side Apply under fieldManager,
+// taking ownership of whatever fields cfg sets.
+func (k *kubernetesClient) PatchNamespace(ctx context.Context, cfg *applyconfigurationscorev1.NamespaceApplyConfiguration, fieldManager string) (*corev1.Namespace, error) {
+	if cfg.Name == nil {
+		return nil, errors.Errorf("apply configuration must set name")
+	}
+
+	opts := applyOpts
+	opts.FieldManager = fieldManager
+
+	ns, err := k.c.CoreV1().Namespaces().Apply(ctx, cfg, opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not apply Namespace %q", *cfg.Name)
+	}
+	return ns, nil
+}
+
+// SupportsServerSideApply reports whether the target apiserver is new enough to support Server-Side
+// Apply (GA since Kubernetes 1.22). Nothing in this package calls it: choosing between
+// PatchPV/PatchPVC/PatchNamespace and the get-mutate-update-retry loop is left to the caller.
+func (k *kubernetesClient) SupportsServerSideApply() (bool, error) {
+	info, err := k.c.Discovery().ServerVersion()
+	if err != nil {
+		return false, errors.Wrapf(err, "could not get server version")
+	}
+
+	major, err := strconv.Atoi(strings.TrimSuffix(info.Major, "+"))
+	if err != nil {
+		return false, errors.Wrapf(err, "could not parse server major version %q", info.Major)
+	}
+	minor, err := strconv.Atoi(strings.TrimSuffix(info.Minor, "+"))
+	if err != nil {
+		return false, errors.Wrapf(err, "could not parse server minor version %q", info.Minor)
+	}
+
+	return major > 1 || (major == 1 && minor >= 22), nil
+}
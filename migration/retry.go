@@ -0,0 +1,52 @@
+package migration
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// retryBackoff is the default exponential backoff used by retryOnConflict.
+var retryBackoff = wait.Backoff{
+	Duration: 100 * time.Millisecond,
+	Factor:   2.0,
+	Jitter:   0.1,
+	Steps:    8,
+}
+
+// retryOnConflict calls fn and retries the whole thing, with exponential backoff, as long as fn
+// returns a retryable error (see isRetryableAPIError) and backoff hasn't been exhausted. It returns
+// early if ctx is done. fn is expected to re-fetch and re-mutate the object itself on each call, the
+// same way k8s.io/client-go/util/retry.RetryOnConflict expects of its callers.
+func retryOnConflict(ctx context.Context, backoff wait.Backoff, fn func() error) error {
+	var lastErr error
+	err := wait.ExponentialBackoffWithContext(ctx, backoff, func(ctx context.Context) (bool, error) {
+		lastErr = fn()
+		switch {
+		case lastErr == nil:
+			return true, nil
+		case isRetryableAPIError(lastErr):
+			log.Debugf("got a retryable error, retrying: %s", lastErr)
+			return false, nil
+		default:
+			return false, lastErr
+		}
+	})
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		return lastErr
+	}
+	return err
+}
+
+// isRetryableAPIError reports whether err is a transient apiserver error worth retrying: a
+// ResourceVersion conflict from another writer, a server timeout, rate limiting, or an internal
+// server error. Anything else (NotFound, Invalid, Forbidden, ...) is treated as permanent.
+func isRetryableAPIError(err error) bool {
+	return apierrors.IsConflict(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsInternalError(err)
+}
@@ -0,0 +1,84 @@
+package migration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var fastRetryBackoff = wait.Backoff{
+	Duration: time.Millisecond,
+	Factor:   1.0,
+	Steps:    5,
+}
+
+func TestRetryOnConflictSucceedsAfterConflicts(t *testing.T) {
+	gr := schema.GroupResource{Resource: "persistentvolumes"}
+	attempts := 0
+	err := retryOnConflict(context.Background(), fastRetryBackoff, func() error {
+		attempts++
+		if attempts < 3 {
+			return apierrors.NewConflict(gr, "pv-1", errors.New("conflict"))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("retryOnConflict() = %v, want nil", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("fn called %d times, want 3", attempts)
+	}
+}
+
+func TestRetryOnConflictReturnsNonRetryableErrorImmediately(t *testing.T) {
+	attempts := 0
+	wantErr := apierrors.NewNotFound(schema.GroupResource{Resource: "persistentvolumes"}, "pv-1")
+	err := retryOnConflict(context.Background(), fastRetryBackoff, func() error {
+		attempts++
+		return wantErr
+	})
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("retryOnConflict() = %v, want %v", err, wantErr)
+	}
+	if attempts != 1 {
+		t.Fatalf("fn called %d times, want 1", attempts)
+	}
+}
+
+func TestRetryOnConflictGivesUpWhenBackoffExhausted(t *testing.T) {
+	gr := schema.GroupResource{Resource: "persistentvolumes"}
+	attempts := 0
+	err := retryOnConflict(context.Background(), fastRetryBackoff, func() error {
+		attempts++
+		return apierrors.NewConflict(gr, "pv-1", errors.New("conflict"))
+	})
+	if err == nil || !apierrors.IsConflict(err) {
+		t.Fatalf("retryOnConflict() = %v, want a conflict error", err)
+	}
+	if attempts != fastRetryBackoff.Steps {
+		t.Fatalf("fn called %d times, want %d", attempts, fastRetryBackoff.Steps)
+	}
+}
+
+func TestRetryOnConflictStopsWhenContextCancelled(t *testing.T) {
+	gr := schema.GroupResource{Resource: "persistentvolumes"}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	attempts := 0
+	err := retryOnConflict(ctx, fastRetryBackoff, func() error {
+		attempts++
+		return apierrors.NewConflict(gr, "pv-1", errors.New("conflict"))
+	})
+	if err == nil {
+		t.Fatal("retryOnConflict() = nil, want an error")
+	}
+	if attempts > 1 {
+		t.Fatalf("fn called %d times after ctx was already cancelled, want at most 1", attempts)
+	}
+}
@@ -0,0 +1,277 @@
+package migration
+
+import (
+	"context"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v8/apis/volumesnapshot/v1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// requireSnapshotClient returns an error if the cluster does not advertise the snapshot.storage.k8s.io
+// API group, since k.snap is nil in that case (see kubernetesClient.snap).
+func (k *kubernetesClient) requireSnapshotClient() error {
+	if k.snap == nil {
+		return errors.Errorf("cluster does not advertise the snapshot.storage.k8s.io API group")
+	}
+	return nil
+}
+
+// CreateVolumeSnapshot creates a VolumeSnapshot and returns it after the server has processed it. It
+// does not fail if the VolumeSnapshot already exists, but grabs the already existing one.
+func (k *kubernetesClient) CreateVolumeSnapshot(ctx context.Context, vs *snapshotv1.VolumeSnapshot) (*snapshotv1.VolumeSnapshot, error) {
+	if err := k.requireSnapshotClient(); err != nil {
+		return nil, err
+	}
+
+	_, err := k.snap.SnapshotV1().VolumeSnapshots(vs.Namespace).Create(ctx, vs, createOpts)
+	if err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return nil, errors.Wrapf(err, "could not create VolumeSnapshot")
+		}
+	}
+
+	vs, err = k.snap.SnapshotV1().VolumeSnapshots(vs.Namespace).Get(ctx, vs.Name, getOpts)
+	if err != nil {
+		return nil, err
+	}
+	return vs, nil
+}
+
+func (k *kubernetesClient) GetVolumeSnapshot(ctx context.Context, name, namespace string) (*snapshotv1.VolumeSnapshot, error) {
+	if err := k.requireSnapshotClient(); err != nil {
+		return nil, err
+	}
+
+	vs, err := k.snap.SnapshotV1().VolumeSnapshots(namespace).Get(ctx, name, getOpts)
+	if err != nil {
+		return nil, err
+	}
+	return vs, nil
+}
+
+func (k *kubernetesClient) DeleteVolumeSnapshot(ctx context.Context, name, namespace string) error {
+	if err := k.requireSnapshotClient(); err != nil {
+		return err
+	}
+
+	err := k.snap.SnapshotV1().VolumeSnapshots(namespace).Delete(ctx, name, deleteOpts)
+	if err != nil && apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// CreateVolumeSnapshotContent creates a VolumeSnapshotContent and returns it after the server has
+// processed it. It does not fail if the VolumeSnapshotContent already exists, but grabs the already
+// existing one.
+func (k *kubernetesClient) CreateVolumeSnapshotContent(ctx context.Context, vsc *snapshotv1.VolumeSnapshotContent) (*snapshotv1.VolumeSnapshotContent, error) {
+	if err := k.requireSnapshotClient(); err != nil {
+		return nil, err
+	}
+
+	_, err := k.snap.SnapshotV1().VolumeSnapshotContents().Create(ctx, vsc, createOpts)
+	if err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return nil, errors.Wrapf(err, "could not create VolumeSnapshotContent")
+		}
+	}
+
+	vsc, err = k.snap.SnapshotV1().VolumeSnapshotContents().Get(ctx, vsc.Name, getOpts)
+	if err != nil {
+		return nil, err
+	}
+	return vsc, nil
+}
+
+func (k *kubernetesClient) GetVolumeSnapshotContent(ctx context.Context, name string) (*snapshotv1.VolumeSnapshotContent, error) {
+	if err := k.requireSnapshotClient(); err != nil {
+		return nil, err
+	}
+
+	vsc, err := k.snap.SnapshotV1().VolumeSnapshotContents().Get(ctx, name, getOpts)
+	if err != nil {
+		return nil, err
+	}
+	return vsc, nil
+}
+
+func (k *kubernetesClient) DeleteVolumeSnapshotContent(ctx context.Context, name string) error {
+	if err := k.requireSnapshotClient(); err != nil {
+		return err
+	}
+
+	err := k.snap.SnapshotV1().VolumeSnapshotContents().Delete(ctx, name, deleteOpts)
+	if err != nil && apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// ImportVolumeSnapshotContent binds a pre-provisioned VolumeSnapshotContent (referring to a CSI
+// snapshot handle taken in the source cluster) to a new PVC, by pointing the PVC's
+// dataSource/dataSourceRef at a VolumeSnapshot that in turn binds to the given content. This is the
+// re-materialization step of the snapshot migration strategy: the content object already exists and
+// carries the underlying snapshot handle, so no new snapshot is taken here.
+func (k *kubernetesClient) ImportVolumeSnapshotContent(ctx context.Context, contentName string,
+	pvc *corev1.PersistentVolumeClaim,
+) (*corev1.PersistentVolumeClaim, error) {
+	vs, err := k.CreateVolumeSnapshot(ctx, &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pvc.Name + "-import",
+			Namespace: pvc.Namespace,
+		},
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			Source: snapshotv1.VolumeSnapshotSource{
+				VolumeSnapshotContentName: &contentName,
+			},
+			// VolumeSnapshotClassName is left nil: the CRD rejects a non-nil empty string, and a
+			// pre-provisioned import has no VolumeSnapshotClass to name anyway.
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not create import VolumeSnapshot for content %q", contentName)
+	}
+
+	apiGroup := snapshotv1.GroupName
+	pvc.Spec.DataSource = &corev1.TypedLocalObjectReference{
+		APIGroup: &apiGroup,
+		Kind:     "VolumeSnapshot",
+		Name:     vs.Name,
+	}
+	pvc.Spec.DataSourceRef = &corev1.TypedObjectReference{
+		APIGroup: &apiGroup,
+		Kind:     "VolumeSnapshot",
+		Name:     vs.Name,
+	}
+
+	return k.CreatePVC(ctx, pvc)
+}
+
+// SnapshotClassForStorageClass returns the name of a VolumeSnapshotClass whose driver matches the
+// given StorageClass's provisioner, if one exists. The migration engine uses this to decide, per PVC,
+// whether the snapshot strategy is available or whether it must fall back to the job-based one.
+func (k *kubernetesClient) SnapshotClassForStorageClass(ctx context.Context, storageClassName string) (string, bool, error) {
+	if err := k.requireSnapshotClient(); err != nil {
+		return "", false, err
+	}
+
+	sc, err := k.c.StorageV1().StorageClasses().Get(ctx, storageClassName, getOpts)
+	if err != nil {
+		return "", false, errors.Wrapf(err, "could not get StorageClass %q", storageClassName)
+	}
+
+	classes, err := k.snap.SnapshotV1().VolumeSnapshotClasses().List(ctx, listOpts)
+	if err != nil {
+		return "", false, errors.Wrapf(err, "could not list VolumeSnapshotClasses")
+	}
+
+	for _, class := range classes.Items {
+		if class.Driver == sc.Provisioner {
+			return class.Name, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// MigrationStrategy identifies which of relokator's PVC migration strategies to use for a given PVC.
+type MigrationStrategy int
+
+const (
+	// StrategyJob copies data through a Job that mounts both the source and target PVC.
+	StrategyJob MigrationStrategy = iota
+	// StrategySnapshot migrates the PVC via a CSI VolumeSnapshot, see MigratePVCViaSnapshot.
+	StrategySnapshot
+)
+
+// SelectMigrationStrategy picks StrategySnapshot for pvc if its StorageClass has a matching
+// VolumeSnapshotClass, and StrategyJob otherwise. Callers drive StrategyJob themselves via
+// CreateJob/WaitForJobComplete; there is no single job-based entry point to call symmetrically with
+// MigratePVCViaSnapshot.
+func (k *kubernetesClient) SelectMigrationStrategy(ctx context.Context, pvc *corev1.PersistentVolumeClaim,
+) (strategy MigrationStrategy, volumeSnapshotClassName string, err error) {
+	if k.snap == nil || pvc.Spec.StorageClassName == nil {
+		return StrategyJob, "", nil
+	}
+
+	storageClassName := *pvc.Spec.StorageClassName
+	class, ok, err := k.SnapshotClassForStorageClass(ctx, storageClassName)
+	if err != nil {
+		return StrategyJob, "", errors.Wrapf(err, "could not check for a VolumeSnapshotClass for StorageClass %q", storageClassName)
+	}
+	if !ok {
+		return StrategyJob, "", nil
+	}
+	return StrategySnapshot, class, nil
+}
+
+// MigratePVCViaSnapshot implements the snapshot-based migration strategy: it snapshots the given PVC
+// using volumeSnapshotClassName, waits for the resulting VolumeSnapshotContent to be bound, and
+// re-materializes it as a new PVC in targetNamespace via ImportVolumeSnapshotContent. It is the
+// counterpart to the data-copy Job used by the existing job-based strategy, and should only be used
+// once SnapshotClassForStorageClass has confirmed a VolumeSnapshotClass is available.
+func (k *kubernetesClient) MigratePVCViaSnapshot(ctx context.Context, pvc *corev1.PersistentVolumeClaim,
+	targetNamespace, volumeSnapshotClassName string,
+) (*corev1.PersistentVolumeClaim, error) {
+	sourceName := pvc.Name
+	vs, err := k.CreateVolumeSnapshot(ctx, &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pvc.Name + "-snapshot",
+			Namespace: pvc.Namespace,
+		},
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			Source: snapshotv1.VolumeSnapshotSource{
+				PersistentVolumeClaimName: &sourceName,
+			},
+			VolumeSnapshotClassName: &volumeSnapshotClassName,
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not snapshot source PVC %q", pvc.Name)
+	}
+
+	contentName, err := k.waitForVolumeSnapshotBound(ctx, vs.Name, vs.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	targetPVC := pvc.DeepCopy()
+	targetPVC.Namespace = targetNamespace
+	targetPVC.ResourceVersion = ""
+	targetPVC.Spec.VolumeName = ""
+
+	return k.ImportVolumeSnapshotContent(ctx, contentName, targetPVC)
+}
+
+// waitForVolumeSnapshotBound polls the VolumeSnapshot named name in namespace ns until the
+// snapshot-controller populates Status.BoundVolumeSnapshotContentName, and returns that content name.
+// Binding happens asynchronously after Create returns, so callers must not read Status off the object
+// CreateVolumeSnapshot handed back.
+func (k *kubernetesClient) waitForVolumeSnapshotBound(ctx context.Context, name, ns string) (string, error) {
+	var contentName string
+	err := wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		vs, err := k.GetVolumeSnapshot(ctx, name, ns)
+		if err != nil {
+			if isRetryableAPIError(err) {
+				log.Debugf("could not get VolumeSnapshot %s/%s, retrying: %s", ns, name, err)
+				return false, nil
+			}
+			return false, errors.Wrapf(err, "could not get VolumeSnapshot %s/%s", ns, name)
+		}
+
+		if vs.Status == nil || vs.Status.BoundVolumeSnapshotContentName == nil {
+			log.Debugf("waiting for VolumeSnapshot %s/%s to bind", ns, name)
+			return false, nil
+		}
+
+		contentName = *vs.Status.BoundVolumeSnapshotContentName
+		return true, nil
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "VolumeSnapshot %s/%s did not bind", ns, name)
+	}
+	return contentName, nil
+}
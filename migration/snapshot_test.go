@@ -0,0 +1,110 @@
+package migration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v8/apis/volumesnapshot/v1"
+	snapshotfake "github.com/kubernetes-csi/external-snapshotter/client/v8/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestClient(snapObjects ...runtime.Object) *kubernetesClient {
+	return &kubernetesClient{
+		c:    fake.NewSimpleClientset(),
+		snap: snapshotfake.NewSimpleClientset(snapObjects...),
+	}
+}
+
+func TestWaitForVolumeSnapshotBoundTimesOutWhenNeverBound(t *testing.T) {
+	vs := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "data-snapshot", Namespace: "default"},
+	}
+	k := newTestClient(vs)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := k.waitForVolumeSnapshotBound(ctx, vs.Name, vs.Namespace)
+	if err == nil {
+		t.Fatal("waitForVolumeSnapshotBound() = nil, want an error for a snapshot that never binds")
+	}
+}
+
+func TestWaitForVolumeSnapshotBoundReturnsContentNameOnceBound(t *testing.T) {
+	contentName := "snapcontent-abc"
+	vs := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "data-snapshot", Namespace: "default"},
+		Status: &snapshotv1.VolumeSnapshotStatus{
+			BoundVolumeSnapshotContentName: &contentName,
+		},
+	}
+	k := newTestClient(vs)
+
+	got, err := k.waitForVolumeSnapshotBound(context.Background(), vs.Name, vs.Namespace)
+	if err != nil {
+		t.Fatalf("waitForVolumeSnapshotBound() = %v, want nil", err)
+	}
+	if got != contentName {
+		t.Fatalf("waitForVolumeSnapshotBound() = %q, want %q", got, contentName)
+	}
+}
+
+func TestImportVolumeSnapshotContentPointsPVCAtTheImportSnapshot(t *testing.T) {
+	k := newTestClient()
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default"},
+	}
+
+	got, err := k.ImportVolumeSnapshotContent(context.Background(), "snapcontent-abc", pvc)
+	if err != nil {
+		t.Fatalf("ImportVolumeSnapshotContent() = %v, want nil", err)
+	}
+
+	wantVSName := "data-import"
+	if got.Spec.DataSource == nil || got.Spec.DataSource.Name != wantVSName {
+		t.Fatalf("PVC.Spec.DataSource = %+v, want Name %q", got.Spec.DataSource, wantVSName)
+	}
+	if got.Spec.DataSourceRef == nil || got.Spec.DataSourceRef.Name != wantVSName {
+		t.Fatalf("PVC.Spec.DataSourceRef = %+v, want Name %q", got.Spec.DataSourceRef, wantVSName)
+	}
+
+	vs, err := k.GetVolumeSnapshot(context.Background(), wantVSName, pvc.Namespace)
+	if err != nil {
+		t.Fatalf("GetVolumeSnapshot() = %v, want nil", err)
+	}
+	if vs.Spec.VolumeSnapshotClassName != nil {
+		t.Fatalf("VolumeSnapshot.Spec.VolumeSnapshotClassName = %q, want nil (the CRD rejects a non-nil empty string)",
+			*vs.Spec.VolumeSnapshotClassName)
+	}
+}
+
+func TestMigratePVCViaSnapshotReturnsImportedPVCOnceBound(t *testing.T) {
+	contentName := "snapcontent-abc"
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default"},
+	}
+	preBound := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: pvc.Name + "-snapshot", Namespace: pvc.Namespace},
+		Status: &snapshotv1.VolumeSnapshotStatus{
+			BoundVolumeSnapshotContentName: &contentName,
+		},
+	}
+	k := newTestClient(preBound)
+
+	got, err := k.MigratePVCViaSnapshot(context.Background(), pvc, "target-ns", "csi-hostpath")
+	if err != nil {
+		t.Fatalf("MigratePVCViaSnapshot() = %v, want nil", err)
+	}
+
+	if got.Namespace != "target-ns" {
+		t.Fatalf("PVC.Namespace = %q, want %q", got.Namespace, "target-ns")
+	}
+	if got.Spec.DataSource == nil || got.Spec.DataSource.Name != "data-import" {
+		t.Fatalf("PVC.Spec.DataSource = %+v, want Name %q", got.Spec.DataSource, "data-import")
+	}
+}
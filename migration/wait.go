@@ -0,0 +1,110 @@
+package migration
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// pollInterval is how often the WaitFor* helpers poll the apiserver for a resource's status.
+const pollInterval = 2 * time.Second
+
+// defaultWaitTimeout bounds how long a WaitFor* helper polls before giving up, so a caller that passes
+// a context without its own deadline still gets a bounded wait instead of blocking forever.
+const defaultWaitTimeout = 10 * time.Minute
+
+// WaitForJobComplete blocks until the Job named name in namespace ns reports Complete, or returns an
+// error if it reports Failed, times out, or ctx is done.
+func (k *kubernetesClient) WaitForJobComplete(ctx context.Context, name, ns string) error {
+	return wait.PollUntilContextTimeout(ctx, pollInterval, defaultWaitTimeout, true, func(ctx context.Context) (bool, error) {
+		job, err := k.GetJob(ctx, name, ns)
+		if err != nil {
+			if isRetryableAPIError(err) {
+				log.Debugf("could not get Job %s/%s, retrying: %s", ns, name, err)
+				return false, nil
+			}
+			return false, errors.Wrapf(err, "could not get Job %s/%s", ns, name)
+		}
+
+		for _, cond := range job.Status.Conditions {
+			if cond.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch cond.Type {
+			case batchv1.JobComplete:
+				return true, nil
+			case batchv1.JobFailed:
+				return false, errors.Errorf("Job %s/%s failed: %s", ns, name, cond.Message)
+			}
+		}
+
+		log.Debugf("waiting for Job %s/%s to complete, active=%d succeeded=%d failed=%d",
+			ns, name, job.Status.Active, job.Status.Succeeded, job.Status.Failed)
+		return false, nil
+	})
+}
+
+// WaitForPVCBound blocks until the PVC named name in namespace ns reports phase Bound, or returns an
+// error if it times out or ctx is done.
+func (k *kubernetesClient) WaitForPVCBound(ctx context.Context, name, ns string) error {
+	return wait.PollUntilContextTimeout(ctx, pollInterval, defaultWaitTimeout, true, func(ctx context.Context) (bool, error) {
+		pvc, err := k.GetPVC(ctx, name, ns)
+		if err != nil {
+			if isRetryableAPIError(err) {
+				log.Debugf("could not get PVC %s/%s, retrying: %s", ns, name, err)
+				return false, nil
+			}
+			return false, errors.Wrapf(err, "could not get PVC %s/%s", ns, name)
+		}
+
+		log.Debugf("waiting for PVC %s/%s to be bound, phase=%s", ns, name, pvc.Status.Phase)
+		return pvc.Status.Phase == corev1.ClaimBound, nil
+	})
+}
+
+// WaitForPVCDeleted blocks until the PVC named name in namespace ns is gone, or returns an error if
+// it times out or ctx is done.
+func (k *kubernetesClient) WaitForPVCDeleted(ctx context.Context, name, ns string) error {
+	return wait.PollUntilContextTimeout(ctx, pollInterval, defaultWaitTimeout, true, func(ctx context.Context) (bool, error) {
+		pvc, err := k.GetPVC(ctx, name, ns)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return true, nil
+			}
+			if isRetryableAPIError(err) {
+				log.Debugf("could not get PVC %s/%s, retrying: %s", ns, name, err)
+				return false, nil
+			}
+			return false, errors.Wrapf(err, "could not get PVC %s/%s", ns, name)
+		}
+
+		log.Debugf("waiting for PVC %s/%s to be deleted, phase=%s", ns, name, pvc.Status.Phase)
+		return false, nil
+	})
+}
+
+// WaitForPodDeleted blocks until the Pod named name in namespace ns is gone, or returns an error if
+// it times out or ctx is done.
+func (k *kubernetesClient) WaitForPodDeleted(ctx context.Context, name, ns string) error {
+	return wait.PollUntilContextTimeout(ctx, pollInterval, defaultWaitTimeout, true, func(ctx context.Context) (bool, error) {
+		pod, err := k.c.CoreV1().Pods(ns).Get(ctx, name, getOpts)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return true, nil
+			}
+			if isRetryableAPIError(err) {
+				log.Debugf("could not get Pod %s/%s, retrying: %s", ns, name, err)
+				return false, nil
+			}
+			return false, errors.Wrapf(err, "could not get Pod %s/%s", ns, name)
+		}
+
+		log.Debugf("waiting for Pod %s/%s to be deleted, phase=%s", ns, name, pod.Status.Phase)
+		return false, nil
+	})
+}
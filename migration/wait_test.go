@@ -0,0 +1,61 @@
+package migration
+
+import (
+	"context"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestKubernetesClient(objects ...runtime.Object) *kubernetesClient {
+	return &kubernetesClient{c: fake.NewSimpleClientset(objects...)}
+}
+
+func TestWaitForJobCompleteReturnsErrorOnJobFailed(t *testing.T) {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "migrate-data", Namespace: "default"},
+		Status: batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "backoff limit exceeded"},
+			},
+		},
+	}
+	k := newTestKubernetesClient(job)
+
+	err := k.WaitForJobComplete(context.Background(), job.Name, job.Namespace)
+	if err == nil {
+		t.Fatal("WaitForJobComplete() = nil, want an error for a failed Job")
+	}
+}
+
+func TestWaitForPVCBoundReturnsOnceBound(t *testing.T) {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default"},
+		Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+	}
+	k := newTestKubernetesClient(pvc)
+
+	if err := k.WaitForPVCBound(context.Background(), pvc.Name, pvc.Namespace); err != nil {
+		t.Fatalf("WaitForPVCBound() = %v, want nil", err)
+	}
+}
+
+func TestWaitForPVCDeletedReturnsImmediatelyWhenAlreadyGone(t *testing.T) {
+	k := newTestKubernetesClient()
+
+	if err := k.WaitForPVCDeleted(context.Background(), "data", "default"); err != nil {
+		t.Fatalf("WaitForPVCDeleted() = %v, want nil for a PVC that is already gone", err)
+	}
+}
+
+func TestWaitForPodDeletedReturnsImmediatelyWhenAlreadyGone(t *testing.T) {
+	k := newTestKubernetesClient()
+
+	if err := k.WaitForPodDeleted(context.Background(), "migrate-data", "default"); err != nil {
+		t.Fatalf("WaitForPodDeleted() = %v, want nil for a Pod that is already gone", err)
+	}
+}